@@ -79,6 +79,22 @@ func (ct *CachedTargets) GetBrokerByKey(key *BrokerKey) (*Broker, bool) {
 	return b, ok
 }
 
+// RangeEventPoliciesForBroker ranges over all EventPolicies attached to the given broker. It is
+// part of ReadonlyTargets so that callers, such as the ingress's authorization check, don't need
+// to go through GetBrokerByKey and reach into Broker.EventPolicies themselves.
+// Do not modify the given EventPolicy copy.
+func (ct *CachedTargets) RangeEventPoliciesForBroker(broker *BrokerKey, f func(*EventPolicy) bool) {
+	b, ok := ct.GetBrokerByKey(broker)
+	if !ok {
+		return
+	}
+	for _, p := range b.EventPolicies {
+		if c := f(p); !c {
+			return
+		}
+	}
+}
+
 // RangeBrokers ranges over all brokers.
 // Do not modify the given Broker copy.
 func (ct *CachedTargets) RangeBrokers(f func(*Broker) bool) {
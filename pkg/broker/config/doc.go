@@ -0,0 +1,29 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config provides read access to the broker/trigger configuration the broker controller
+// reconciles into the targets configmap, plus the in-memory cache (CachedTargets) that mounts it
+// for the ingress and fanout binaries.
+//
+// The TargetsConfig/Broker/Target/DecoupleQueue/DeadLetterQueue/EventPolicy/KafkaQueue message
+// types and the ReadonlyTargets interface are generated from this package's .proto definitions
+// elsewhere in the broker controller's module; this checkout contains only CachedTargets, so
+// consumers of those types (pkg/broker/ingress) can't build standalone here. Any change to a
+// field consumed under pkg/broker/ingress (EventPolicy, DecoupleQueue.BackoffPolicy/BackoffDelay/
+// Retry/Timeout/Type, KafkaQueue, DeadLetterQueue) must also land in the .proto, the generated
+// *.pb.go, and the broker controller reconciler that populates it from the Broker/Trigger CRDs;
+// none of those are present in this checkout to update alongside it.
+package config
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/google/knative-gcp/pkg/apis/feature"
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// ErrUnauthorized is returned by Send when the event's authenticated subject isn't authorized to
+// publish to the target broker's decouple topic.
+var ErrUnauthorized = errors.New("subject is not authorized to publish to this broker")
+
+// subjectKey is the context key under which the authenticated subject of the incoming request is
+// stored.
+type subjectKey struct{}
+
+// WithSubject returns a copy of ctx carrying the authenticated subject of the incoming request,
+// as extracted by OIDCAuthenticationHandler.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext returns the authenticated subject stored in ctx by WithSubject, and whether
+// one was present. A request that didn't carry a valid bearer token has no subject.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(string)
+	return subject, ok
+}
+
+// isAuthorized reports whether the subject in ctx is allowed to publish event to broker, per the
+// broker's EventPolicies and, when it has none, the cluster-wide authorization-default-mode
+// feature flag.
+func (m *multiTopicDecoupleSink) isAuthorized(ctx context.Context, broker *config.BrokerKey, event *cev2.Event) bool {
+	var policies []*config.EventPolicy
+	m.brokerConfig.RangeEventPoliciesForBroker(broker, func(p *config.EventPolicy) bool {
+		policies = append(policies, p)
+		return true
+	})
+
+	if len(policies) == 0 {
+		subject, _ := SubjectFromContext(ctx)
+		return defaultModeAllows(feature.FromContext(ctx).AuthorizationDefaultMode, broker.Namespace(), subject)
+	}
+
+	subject, ok := SubjectFromContext(ctx)
+	if !ok {
+		// Policies are configured but the request carries no authenticated subject to check them
+		// against: there's nothing it could legitimately match.
+		return false
+	}
+	return policiesAllow(ctx, policies, subject, event)
+}
+
+// defaultModeAllows implements the authorization-default-mode fallback used for brokers with zero
+// EventPolicies. An empty/unrecognized mode preserves the pre-EventPolicy behavior of allowing
+// every publish, so rolling out this feature doesn't turn existing brokers into a default-deny
+// wall until an operator explicitly opts in to enforcement.
+func defaultModeAllows(mode feature.AuthorizationDefaultMode, brokerNamespace, subject string) bool {
+	switch mode {
+	case "":
+		return true
+	case feature.AuthorizationAllowAll:
+		return true
+	case feature.AuthorizationAllowSameNamespace:
+		ns, ok := namespaceFromSubject(subject)
+		return ok && ns == brokerNamespace
+	default:
+		return false
+	}
+}
+
+// policiesAllow reports whether subject is authorized to send event by at least one of policies:
+// the subject must be listed on the policy, and the event must pass that policy's filter.
+func policiesAllow(ctx context.Context, policies []*config.EventPolicy, subject string, event *cev2.Event) bool {
+	for _, policy := range policies {
+		if !subjectAuthorized(policy, subject) {
+			continue
+		}
+		if eventFilterFunc(ctx, policy.FilterAttributes, event) {
+			return true
+		}
+	}
+	return false
+}
+
+func subjectAuthorized(policy *config.EventPolicy, subject string) bool {
+	for _, s := range policy.Subjects {
+		if s == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// k8sServiceAccountSubjectPrefix is the prefix Knative's OIDC trust domain uses for a Kubernetes
+// ServiceAccount's subject claim: "system:serviceaccount:<namespace>:<name>".
+const k8sServiceAccountSubjectPrefix = "system:serviceaccount:"
+
+// namespaceFromSubject extracts the namespace encoded in a ServiceAccount OIDC subject, returning
+// ok=false for any subject that doesn't follow that convention (e.g. a Google user or service
+// account identity, which has no namespace to compare).
+func namespaceFromSubject(subject string) (namespace string, ok bool) {
+	rest := strings.TrimPrefix(subject, k8sServiceAccountSubjectPrefix)
+	if rest == subject {
+		return "", false
+	}
+	parts := strings.SplitN(rest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
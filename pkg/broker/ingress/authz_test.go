@@ -0,0 +1,158 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"testing"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/google/knative-gcp/pkg/apis/feature"
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+func TestNamespaceFromSubject(t *testing.T) {
+	tests := []struct {
+		name      string
+		subject   string
+		wantNS    string
+		wantFound bool
+	}{
+		{
+			name:      "k8s service account subject",
+			subject:   "system:serviceaccount:my-ns:my-sa",
+			wantNS:    "my-ns",
+			wantFound: true,
+		},
+		{
+			name:      "google user subject",
+			subject:   "user@example.com",
+			wantFound: false,
+		},
+		{
+			name:      "empty subject",
+			subject:   "",
+			wantFound: false,
+		},
+		{
+			name:      "missing namespace segment",
+			subject:   "system:serviceaccount:",
+			wantFound: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ns, ok := namespaceFromSubject(test.subject)
+			if ok != test.wantFound || ns != test.wantNS {
+				t.Errorf("namespaceFromSubject(%q) = (%q, %v), want (%q, %v)", test.subject, ns, ok, test.wantNS, test.wantFound)
+			}
+		})
+	}
+}
+
+func TestDefaultModeAllows(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      feature.AuthorizationDefaultMode
+		namespace string
+		subject   string
+		want      bool
+	}{
+		{
+			name: "unset mode preserves pre-EventPolicy allow-all behavior",
+			mode: "",
+			want: true,
+		},
+		{
+			name: "explicit allow-all",
+			mode: feature.AuthorizationAllowAll,
+			want: true,
+		},
+		{
+			name:      "allow-same-namespace with matching namespace",
+			mode:      feature.AuthorizationAllowSameNamespace,
+			namespace: "my-ns",
+			subject:   "system:serviceaccount:my-ns:my-sa",
+			want:      true,
+		},
+		{
+			name:      "allow-same-namespace with different namespace",
+			mode:      feature.AuthorizationAllowSameNamespace,
+			namespace: "my-ns",
+			subject:   "system:serviceaccount:other-ns:my-sa",
+			want:      false,
+		},
+		{
+			name:      "allow-same-namespace with unauthenticated subject",
+			mode:      feature.AuthorizationAllowSameNamespace,
+			namespace: "my-ns",
+			subject:   "",
+			want:      false,
+		},
+		{
+			name: "unrecognized mode defaults to deny",
+			mode: feature.AuthorizationDefaultMode("bogus-mode"),
+			want: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := defaultModeAllows(test.mode, test.namespace, test.subject); got != test.want {
+				t.Errorf("defaultModeAllows(%v, %q, %q) = %v, want %v", test.mode, test.namespace, test.subject, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPoliciesAllow(t *testing.T) {
+	allowAllFilter := func(ctx context.Context, attrs map[string]string, event *cev2.Event) bool { return true }
+	denyAllFilter := func(ctx context.Context, attrs map[string]string, event *cev2.Event) bool { return false }
+
+	policies := []*config.EventPolicy{
+		{Subjects: []string{"system:serviceaccount:my-ns:producer"}},
+	}
+
+	event := cev2.NewEvent()
+	event.SetID("test-id")
+	event.SetType("test.type")
+	event.SetSource("test-source")
+
+	restore := eventFilterFunc
+	defer func() { eventFilterFunc = restore }()
+
+	t.Run("authorized subject and passing filter", func(t *testing.T) {
+		eventFilterFunc = allowAllFilter
+		if !policiesAllow(context.Background(), policies, "system:serviceaccount:my-ns:producer", &event) {
+			t.Error("expected policiesAllow to return true")
+		}
+	})
+
+	t.Run("authorized subject but failing filter", func(t *testing.T) {
+		eventFilterFunc = denyAllFilter
+		if policiesAllow(context.Background(), policies, "system:serviceaccount:my-ns:producer", &event) {
+			t.Error("expected policiesAllow to return false")
+		}
+	})
+
+	t.Run("unauthorized subject", func(t *testing.T) {
+		eventFilterFunc = allowAllFilter
+		if policiesAllow(context.Background(), policies, "system:serviceaccount:my-ns:someone-else", &event) {
+			t.Error("expected policiesAllow to return false")
+		}
+	})
+}
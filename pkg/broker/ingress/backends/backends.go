@@ -0,0 +1,77 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backends provides a registry of DecoupleSink implementations, keyed by the transport
+// declared on a broker's DecoupleQueue, so the ingress can serve brokers backed by Pub/Sub, Kafka,
+// or other queueing systems side by side.
+package backends
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
+)
+
+// Config bundles everything a Factory might need to build a DecoupleSink. Backends ignore the
+// fields that aren't relevant to them.
+type Config struct {
+	// BrokerConfig holds configurations for all brokers, as populated by the broker controller.
+	BrokerConfig config.ReadonlyTargets
+	// Pubsub is the client used by the Pub/Sub backend.
+	Pubsub *pubsub.Client
+	// PublishSettings is the Pub/Sub PublishSettings applied to every broker's topic.
+	PublishSettings pubsub.PublishSettings
+	// KafkaSecretLookup resolves the SASL credentials referenced by a broker's
+	// KafkaQueue.SaslSecretRef. Required only by the Kafka backend, and only for brokers that
+	// configure SASL.
+	KafkaSecretLookup KafkaSecretLookup
+}
+
+// Factory builds a DecoupleSink for a single backend type.
+type Factory func(ctx context.Context, cfg Config) (ingress.DecoupleSink, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[config.DecoupleQueue_Type]Factory{}
+)
+
+// Register associates a Factory with a DecoupleQueue_Type. It's called from the init() of each
+// backend implementation and panics on a duplicate registration, which would indicate a
+// programming error.
+func Register(t config.DecoupleQueue_Type, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[t]; ok {
+		panic(fmt.Sprintf("backend already registered for type %v", t))
+	}
+	registry[t] = f
+}
+
+// New builds the DecoupleSink registered for t.
+func New(ctx context.Context, t config.DecoupleQueue_Type, cfg Config) (ingress.DecoupleSink, error) {
+	registryMu.RLock()
+	f, ok := registry[t]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no DecoupleSink backend registered for type %v", t)
+	}
+	return f(ctx, cfg)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	kafka_sarama "github.com/cloudevents/sdk-go/protocol/kafka_sarama/v2"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// KafkaSecretLookup resolves the username/password referenced by a KafkaQueue's SaslSecretRef.
+// It's typically backed by a Kubernetes Secret lister. A nil lookup is only valid for brokers
+// whose KafkaQueue doesn't configure SASL.
+type KafkaSecretLookup func(ctx context.Context, ref *config.SecretRef) (username, password string, err error)
+
+func init() {
+	Register(config.DecoupleQueue_KAFKA, newKafkaDecoupleSink)
+}
+
+func newKafkaDecoupleSink(ctx context.Context, cfg Config) (ingress.DecoupleSink, error) {
+	return &kafkaDecoupleSink{
+		brokerConfig:         cfg.BrokerConfig,
+		secretLookup:         cfg.KafkaSecretLookup,
+		enableEventFiltering: ingress.EventFilteringEnabled(),
+		producers:            make(map[config.BrokerKey]*kafkaProducer),
+	}, nil
+}
+
+// kafkaProducer pairs a cached sarama.SyncProducer with the config fingerprint it was built from,
+// so getOrCreateProducer can tell when a broker's KafkaQueue has changed underneath it.
+type kafkaProducer struct {
+	producer    sarama.SyncProducer
+	fingerprint string
+}
+
+// kafkaDecoupleSink implements ingress.DecoupleSink for brokers whose DecoupleQueue.Type is
+// config.DecoupleQueue_KAFKA, writing the event to a broker-specific Kafka topic via the
+// cloudevents Kafka protocol binding. It mirrors multiTopicDecoupleSink's caching and pre-filter
+// behavior for the Pub/Sub backend.
+type kafkaDecoupleSink struct {
+	brokerConfig config.ReadonlyTargets
+	secretLookup KafkaSecretLookup
+	// TODO(#1804): remove this field when enabling the feature by default.
+	enableEventFiltering bool
+
+	producers    map[config.BrokerKey]*kafkaProducer
+	producersMut sync.RWMutex
+}
+
+var _ ingress.DecoupleSink = (*kafkaDecoupleSink)(nil)
+
+func (k *kafkaDecoupleSink) Send(ctx context.Context, broker *config.BrokerKey, event cev2.Event) protocol.Result {
+	brokerConfig, ok := k.brokerConfig.GetBrokerByKey(broker)
+	if !ok {
+		return fmt.Errorf("%q: %w", broker, ingress.ErrNotFound)
+	}
+	dq := brokerConfig.DecoupleQueue
+	if dq == nil || dq.Kafka == nil || dq.Kafka.Topic == "" {
+		return fmt.Errorf("kafka decouple queue of %q: %w", broker, ingress.ErrIncomplete)
+	}
+
+	// TODO(#1804): remove this check when enabling the feature by default.
+	if k.enableEventFiltering && !k.hasTrigger(ctx, &event) {
+		logging.FromContext(ctx).Debug("Filtering target-less event at ingress", zap.String("Eventid", event.ID()))
+		return nil
+	}
+
+	producer, err := k.getOrCreateProducer(ctx, broker, dq.Kafka)
+	if err != nil {
+		return err
+	}
+
+	msg := &sarama.ProducerMessage{Topic: dq.Kafka.Topic}
+	if err := kafka_sarama.WriteProducerMessage(ctx, binding.ToMessage(&event), msg); err != nil {
+		return err
+	}
+	_, _, err = producer.SendMessage(msg)
+	return err
+}
+
+// hasTrigger checks given event against all targets to see if it will pass any of their filters,
+// mirroring multiTopicDecoupleSink.hasTrigger for the Pub/Sub backend.
+func (k *kafkaDecoupleSink) hasTrigger(ctx context.Context, event *cev2.Event) bool {
+	hasTrigger := false
+	k.brokerConfig.RangeAllTargets(func(target *config.Target) bool {
+		if filter.PassFilter(ctx, target.FilterAttributes, event) {
+			hasTrigger = true
+			return false
+		}
+		return true
+	})
+	return hasTrigger
+}
+
+// kafkaConfigFingerprint identifies the subset of a KafkaQueue that a sarama.SyncProducer is
+// built from, so getOrCreateProducer can detect a config change and rebuild instead of reusing a
+// producer pointed at stale brokers or a stale secret reference. The secret's contents are not
+// part of the fingerprint, so credentials changed in place under the same reference are not
+// detected.
+func kafkaConfigFingerprint(cfg *config.KafkaQueue) string {
+	ref := ""
+	if cfg.SaslSecretRef != nil {
+		ref = cfg.SaslSecretRef.Name + "/" + cfg.SaslSecretRef.Key
+	}
+	return strings.Join(cfg.BootstrapServers, ",") + "|" + ref
+}
+
+func (k *kafkaDecoupleSink) getOrCreateProducer(ctx context.Context, broker *config.BrokerKey, kafkaCfg *config.KafkaQueue) (sarama.SyncProducer, error) {
+	fingerprint := kafkaConfigFingerprint(kafkaCfg)
+
+	k.producersMut.RLock()
+	cached, ok := k.producers[*broker]
+	k.producersMut.RUnlock()
+	if ok && cached.fingerprint == fingerprint {
+		return cached.producer, nil
+	}
+
+	k.producersMut.Lock()
+	defer k.producersMut.Unlock()
+	if cached, ok := k.producers[*broker]; ok {
+		if cached.fingerprint == fingerprint {
+			return cached.producer, nil
+		}
+		// Config changed underneath us (e.g. bootstrap servers or SASL secret ref): close the
+		// stale producer rather than leaking its connections.
+		if err := cached.producer.Close(); err != nil {
+			logging.FromContext(ctx).Warn("error closing stale kafka producer", zap.String("broker", broker.PersistenceString()), zap.Error(err))
+		}
+	}
+
+	saramaCfg := sarama.NewConfig()
+	saramaCfg.Producer.Return.Successes = true
+	if kafkaCfg.SaslSecretRef != nil {
+		if k.secretLookup == nil {
+			return nil, fmt.Errorf("kafka SASL secret ref set for %q but no secret lookup is configured", broker)
+		}
+		username, password, err := k.secretLookup(ctx, kafkaCfg.SaslSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("resolving kafka SASL secret for %q: %w", broker, err)
+		}
+		saramaCfg.Net.SASL.Enable = true
+		saramaCfg.Net.SASL.User = username
+		saramaCfg.Net.SASL.Password = password
+	}
+
+	producer, err := sarama.NewSyncProducer(kafkaCfg.BootstrapServers, saramaCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating kafka producer for %q: %w", broker, err)
+	}
+	k.producers[*broker] = &kafkaProducer{producer: producer, fingerprint: fingerprint}
+	return producer, nil
+}
+
+// Close closes every cached sarama.SyncProducer. Callers should invoke it during ingress shutdown
+// to avoid leaking Kafka connections.
+func (k *kafkaDecoupleSink) Close() error {
+	k.producersMut.Lock()
+	defer k.producersMut.Unlock()
+	var firstErr error
+	for broker, cached := range k.producers {
+		if err := cached.producer.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("closing kafka producer for %q: %w", broker, err)
+		}
+	}
+	return firstErr
+}
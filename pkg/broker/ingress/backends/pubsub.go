@@ -0,0 +1,36 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backends
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
+)
+
+func init() {
+	Register(config.DecoupleQueue_PUBSUB, newPubsubDecoupleSink)
+}
+
+func newPubsubDecoupleSink(ctx context.Context, cfg Config) (ingress.DecoupleSink, error) {
+	if cfg.Pubsub == nil {
+		return nil, fmt.Errorf("pubsub backend requires a non-nil pubsub client")
+	}
+	return ingress.NewMultiTopicDecoupleSink(ctx, cfg.BrokerConfig, cfg.Pubsub, cfg.PublishSettings), nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// DecoupleSink accepts an incoming event for a broker and decouples its delivery from the
+// producer, typically by writing it to a durable queue that is later fanned out to triggers. See
+// package pkg/broker/ingress/backends for the registry that builds the implementation registered
+// for a given config.Broker.DecoupleQueue.Type; routing each broker to the sink for its own type
+// is not done by the registry.
+type DecoupleSink interface {
+	// Send sends the given event to the given broker's decouple queue.
+	Send(ctx context.Context, broker *config.BrokerKey, event cev2.Event) protocol.Result
+	// Close releases any resources the backend holds open across Sends (e.g. cached topics or
+	// producer connections). The ingress's main binary must call it during shutdown.
+	Close() error
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+var (
+	probeLatencyM = stats.Float64("probe_latency", "The latency of a decouple topic health probe", "ms")
+	probeSuccessM = stats.Int64("probe_success", "Whether the latest decouple topic health probe succeeded (1) or failed (0)", "1")
+
+	namespaceKey  = tag.MustNewKey("namespace")
+	brokerNameKey = tag.MustNewKey("name")
+)
+
+func init() {
+	if err := view.Register(
+		&view.View{
+			Name:        "broker_ingress/" + probeLatencyM.Name(),
+			Description: probeLatencyM.Description(),
+			Measure:     probeLatencyM,
+			Aggregation: view.Distribution(0, 10, 25, 50, 100, 200, 500, 1000, 2500, 5000, 10000),
+			TagKeys:     []tag.Key{namespaceKey, brokerNameKey},
+		},
+		&view.View{
+			Name:        "broker_ingress/" + probeSuccessM.Name(),
+			Description: probeSuccessM.Description(),
+			Measure:     probeSuccessM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{namespaceKey, brokerNameKey},
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// recordProbeMetrics records the outcome of a single decouple topic health probe, tagged by the
+// probed broker's namespace and name.
+func recordProbeMetrics(ctx context.Context, broker config.BrokerKey, latency time.Duration, success bool) {
+	successVal := int64(0)
+	if success {
+		successVal = 1
+	}
+	ctx, err := tag.New(ctx, tag.Insert(namespaceKey, broker.Namespace()), tag.Insert(brokerNameKey, broker.Name()))
+	if err != nil {
+		return
+	}
+	stats.Record(ctx, probeLatencyM.M(float64(latency.Milliseconds())), probeSuccessM.M(successVal))
+}
@@ -21,10 +21,13 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	cev2 "github.com/cloudevents/sdk-go/v2"
@@ -38,7 +41,50 @@ import (
 
 const projectEnvKey = "PROJECT_ID"
 
-// NewMultiTopicDecoupleSink creates a new multiTopicDecoupleSink.
+// maxRetryBackoffDelay caps the delay an exponential backoff reaches by
+// doubling. The first delay is the configured backoffDelay as is.
+const maxRetryBackoffDelay = 10 * time.Second
+
+// defaultRetryPolicy is used for brokers whose DecoupleQueue doesn't specify
+// a retry policy, preserving the historical at-most-once-attempt behavior.
+var defaultRetryPolicy = retryPolicy{maxRetries: 0}
+
+// retryPolicy captures the per-broker publish retry behavior sourced from
+// config.Broker.DecoupleQueue.
+type retryPolicy struct {
+	backoffPolicy config.BackoffPolicy
+	backoffDelay  time.Duration
+	maxRetries    int32
+	// timeout bounds each individual Publish attempt, applied via context.WithTimeout. It's kept
+	// on the policy rather than on the shared *pubsub.Topic's PublishSettings: the pubsub client
+	// snapshots PublishSettings when the topic's bundler first starts, so mutating it after the
+	// fact to react to a config change silently does nothing, and doing so concurrently with an
+	// in-flight Publish on the same topic is a data race.
+	timeout time.Duration
+}
+
+// retryPolicyFromDecoupleQueue builds a retryPolicy from a broker's decouple
+// queue config, falling back to defaultRetryPolicy for unset fields.
+func retryPolicyFromDecoupleQueue(dq *config.DecoupleQueue) retryPolicy {
+	policy := defaultRetryPolicy
+	if dq == nil {
+		return policy
+	}
+	policy.backoffPolicy = dq.BackoffPolicy
+	policy.maxRetries = dq.Retry
+	if dq.BackoffDelay != nil {
+		policy.backoffDelay = dq.BackoffDelay.AsDuration()
+	}
+	if dq.Timeout != nil {
+		policy.timeout = dq.Timeout.AsDuration()
+	}
+	return policy
+}
+
+var _ DecoupleSink = (*multiTopicDecoupleSink)(nil)
+
+// NewMultiTopicDecoupleSink creates a new multiTopicDecoupleSink, the Pub/Sub backed DecoupleSink.
+// See package pkg/broker/ingress/backends for wiring up a DecoupleSink from broker config.
 func NewMultiTopicDecoupleSink(
 	ctx context.Context,
 	brokerConfig config.ReadonlyTargets,
@@ -50,7 +96,9 @@ func NewMultiTopicDecoupleSink(
 		publishSettings: publishSettings,
 		brokerConfig:    brokerConfig,
 		// TODO(#1118): remove Topic when broker config is removed
-		topics: make(map[config.BrokerKey]*pubsub.Topic),
+		topics:           make(map[config.BrokerKey]*pubsub.Topic),
+		retries:          make(map[config.BrokerKey]retryPolicy),
+		deadLetterTopics: make(map[config.BrokerKey]*pubsub.Topic),
 		// TODO(#1804): remove this field when enabling the feature by default.
 		enableEventFiltering: enableEventFilterFunc(),
 	}
@@ -60,21 +108,32 @@ func NewMultiTopicDecoupleSink(
 // to the broker to which the events are sent.
 type multiTopicDecoupleSink struct {
 	// pubsub talks to pubsub.
-	pubsub          *pubsub.Client
+	pubsub *pubsub.Client
+	// publishSettings is applied to every broker's topic; a DecoupleQueue's
+	// Timeout is applied per publish attempt via retryPolicy instead.
 	publishSettings pubsub.PublishSettings
 	// map from brokers to topics
-	topics    map[config.BrokerKey]*pubsub.Topic
+	topics map[config.BrokerKey]*pubsub.Topic
+	// map from brokers to their publish retry policy, kept in lockstep with topics.
+	retries   map[config.BrokerKey]retryPolicy
 	topicsMut sync.RWMutex
+	// map from brokers to their dead-letter topic, populated lazily on first terminal publish
+	// failure for a broker that has one configured.
+	deadLetterTopics map[config.BrokerKey]*pubsub.Topic
+	dlqMut           sync.RWMutex
 	// brokerConfig holds configurations for all brokers. It's a view of a configmap populated by
 	// the broker controller.
 	brokerConfig config.ReadonlyTargets
 	// TODO(#1804): remove this field when enabling the feature by default.
 	enableEventFiltering bool
+	// prober reports whether each broker's decouple topic is currently reachable. It's nil unless
+	// StartProber was called, in which case Send consults it to fail fast on dead topics.
+	prober *prober
 }
 
 // Send sends incoming event to its corresponding pubsub topic based on which broker it belongs to.
 func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker *config.BrokerKey, event cev2.Event) protocol.Result {
-	topic, err := m.getTopicForBroker(ctx, broker)
+	topic, policy, err := m.getTopicForBroker(ctx, broker)
 	if err != nil {
 		trace.FromContext(ctx).Annotate(
 			[]trace.Attribute{
@@ -85,6 +144,19 @@ func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker *config.Broker
 		return err
 	}
 
+	if m.prober != nil {
+		if status, ok := m.prober.Status(*broker); ok && !status.Ready {
+			return ErrNotReady
+		}
+	}
+
+	// Check authorization before the target-less filter below, so that an unauthorized publish is
+	// always rejected rather than silently accepted whenever it happens not to match any trigger.
+	if !m.isAuthorized(ctx, broker, &event) {
+		logging.FromContext(ctx).Debug("Rejecting unauthorized publish", zap.String("Eventid", event.ID()))
+		return ErrUnauthorized
+	}
+
 	// Check to see if there are any triggers interested in this event. If not, no need to send this
 	// to the decouple topic.
 	// TODO(#1804): remove first check when enabling the feature by default.
@@ -99,10 +171,146 @@ func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker *config.Broker
 		return err
 	}
 
-	_, err = topic.Publish(ctx, msg).Get(ctx)
+	if err := publishWithRetry(ctx, topic, msg, policy); err != nil {
+		return m.handlePublishFailure(ctx, broker, &event, err)
+	}
+	return nil
+}
+
+// Close stops every cached decouple and dead-letter topic, flushing any buffered publishes. The
+// ingress's main binary must call it during shutdown.
+func (m *multiTopicDecoupleSink) Close() error {
+	m.topicsMut.Lock()
+	for _, topic := range m.topics {
+		topic.Stop()
+	}
+	m.topicsMut.Unlock()
+
+	m.dlqMut.Lock()
+	defer m.dlqMut.Unlock()
+	for _, topic := range m.deadLetterTopics {
+		topic.Stop()
+	}
+	return nil
+}
+
+// publishWithRetry publishes msg to topic, retrying Send failures according to policy. The delay
+// between attempts starts at policy.backoffDelay and, for an exponential policy, doubles after
+// each attempt up to maxRetryBackoffDelay. A non-retryable error (e.g. NotFound, PermissionDenied)
+// is returned immediately without consuming a retry attempt.
+func publishWithRetry(ctx context.Context, topic *pubsub.Topic, msg *pubsub.Message, policy retryPolicy) error {
+	delay := policy.backoffDelay
+	for attempt := int32(0); ; attempt++ {
+		err := publishOnce(ctx, topic, msg, policy.timeout)
+		if err == nil || attempt >= policy.maxRetries || !isRetryablePublishErr(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if policy.backoffPolicy == config.BackoffPolicy_BACKOFF_POLICY_EXPONENTIAL {
+			delay *= 2
+			if delay > maxRetryBackoffDelay {
+				delay = maxRetryBackoffDelay
+			}
+		}
+	}
+}
+
+// publishOnce issues a single Publish attempt, bounding it by timeout if positive. timeout is
+// applied per-call via context.WithTimeout rather than topic.PublishSettings.Timeout, since the
+// latter is only read once when the topic's bundler starts.
+func publishOnce(ctx context.Context, topic *pubsub.Topic, msg *pubsub.Message, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err := topic.Publish(ctx, msg).Get(ctx)
 	return err
 }
 
+// isRetryablePublishErr reports whether a failed Publish is worth retrying. Errors like NotFound
+// or PermissionDenied indicate a misconfigured topic that won't start working on its own.
+func isRetryablePublishErr(err error) bool {
+	switch status.Code(err) {
+	case codes.NotFound, codes.PermissionDenied:
+		return false
+	default:
+		return true
+	}
+}
+
+// handlePublishFailure is called when publishing event to the broker's decouple topic has failed
+// terminally (retries exhausted, or a non-retryable error). If the broker has a ready
+// DeadLetterQueue, event is re-serialized with extra `knativeerror*` extensions describing
+// publishErr and diverted there; Send then reports success to the caller. Otherwise publishErr is
+// returned unchanged, preserving the pre-DLQ behavior.
+func (m *multiTopicDecoupleSink) handlePublishFailure(ctx context.Context, broker *config.BrokerKey, event *cev2.Event, publishErr error) protocol.Result {
+	dlqTopic, ok, err := m.getDeadLetterTopicForBroker(ctx, broker)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to look up dead letter topic for broker", zap.String("broker", broker.PersistenceString()), zap.Error(err))
+	}
+	if !ok {
+		return publishErr
+	}
+
+	dead := event.Clone()
+	dead.SetExtension("knativeerrordest", broker.PersistenceString())
+	dead.SetExtension("knativeerrorcode", status.Code(publishErr).String())
+	dead.SetExtension("knativeerrormessage", publishErr.Error())
+
+	dt := extensions.FromSpanContext(trace.FromContext(ctx).SpanContext())
+	msg := new(pubsub.Message)
+	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(&dead), msg, dt.WriteTransformer()); err != nil {
+		logging.FromContext(ctx).Error("unable to encode event for dead letter topic", zap.Error(err))
+		return publishErr
+	}
+
+	if _, err := dlqTopic.Publish(ctx, msg).Get(ctx); err != nil {
+		logging.FromContext(ctx).Error("unable to publish to dead letter topic", zap.String("broker", broker.PersistenceString()), zap.Error(err))
+		return publishErr
+	}
+	return nil
+}
+
+// getDeadLetterTopicForBroker returns the cached dead-letter topic for broker, lazily creating or
+// refreshing it from broker config. ok is false when the broker has no ready DeadLetterQueue, in
+// which case callers should preserve the original publish error.
+func (m *multiTopicDecoupleSink) getDeadLetterTopicForBroker(ctx context.Context, broker *config.BrokerKey) (topic *pubsub.Topic, ok bool, err error) {
+	brokerConfig, found := m.brokerConfig.GetBrokerByKey(broker)
+	if !found {
+		return nil, false, nil
+	}
+	dlq := brokerConfig.DeadLetterQueue
+	if dlq == nil || dlq.Topic == "" || dlq.State != config.State_READY {
+		return nil, false, nil
+	}
+
+	m.dlqMut.RLock()
+	cached, exists := m.deadLetterTopics[*broker]
+	m.dlqMut.RUnlock()
+	if exists && cached.ID() == dlq.Topic {
+		return cached, true, nil
+	}
+
+	m.dlqMut.Lock()
+	defer m.dlqMut.Unlock()
+	if cached, exists := m.deadLetterTopics[*broker]; exists {
+		if cached.ID() == dlq.Topic {
+			return cached, true, nil
+		}
+		cached.Stop()
+	}
+	topic = m.pubsub.Topic(dlq.Topic)
+	m.deadLetterTopics[*broker] = topic
+	return topic, true, nil
+}
+
 // eventFilterFunc is used to see if a target is interested in an event.
 // It is used as a vaiable to allow stubbing out in unit tests.
 var eventFilterFunc = filter.PassFilter
@@ -117,6 +325,14 @@ func isEventFilteringEnabled() bool {
 	return os.Getenv("ENABLE_INGRESS_EVENT_FILTERING") == "true"
 }
 
+// EventFilteringEnabled reports whether target-less event filtering (#1804) is currently enabled
+// for the ingress. Other DecoupleSink backends (see package .../ingress/backends) call this so
+// their own pre-filtering stays consistent with the Pub/Sub backend's.
+// TODO(#1804): remove this function when enabling the feature by default.
+func EventFilteringEnabled() bool {
+	return enableEventFilterFunc()
+}
+
 // hasTrigger checks given event against all targets to see if it will pass any of their filters.
 // If one is fouund, hasTrigger returns true.
 func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, event *cev2.Event) bool {
@@ -133,69 +349,73 @@ func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, event *cev2.Eve
 	return hasTrigger
 }
 
-// getTopicForBroker finds the corresponding decouple topic for the broker from the mounted broker configmap volume.
-func (m *multiTopicDecoupleSink) getTopicForBroker(ctx context.Context, broker *config.BrokerKey) (*pubsub.Topic, error) {
-	topicID, err := m.getTopicIDForBroker(ctx, broker)
-	if err != nil {
-		return nil, err
-	}
-
-	if topic, ok := m.getExistingTopic(broker); ok {
-		// Check that the broker's topic ID hasn't changed.
-		if topic.ID() == topicID {
-			return topic, nil
-		}
-	}
-
-	// Topic needs to be created or updated.
+// getTopicForBroker finds the corresponding decouple topic for the broker from the mounted broker configmap volume,
+// along with its publish retry policy. It always goes through updateTopicForBroker so that a
+// retry/backoff/timeout change in the broker's DecoupleQueue takes effect on the next Send, not
+// just when the topic ID itself changes.
+func (m *multiTopicDecoupleSink) getTopicForBroker(ctx context.Context, broker *config.BrokerKey) (*pubsub.Topic, retryPolicy, error) {
 	return m.updateTopicForBroker(ctx, broker)
 }
 
-func (m *multiTopicDecoupleSink) updateTopicForBroker(ctx context.Context, broker *config.BrokerKey) (*pubsub.Topic, error) {
+func (m *multiTopicDecoupleSink) updateTopicForBroker(ctx context.Context, broker *config.BrokerKey) (*pubsub.Topic, retryPolicy, error) {
 	m.topicsMut.Lock()
 	defer m.topicsMut.Unlock()
-	// Fetch latest decouple topic ID under lock.
-	topicID, err := m.getTopicIDForBroker(ctx, broker)
+	// Fetch latest decouple queue config under lock.
+	dq, err := m.getDecoupleQueueForBroker(ctx, broker)
 	if err != nil {
-		return nil, err
+		return nil, retryPolicy{}, err
 	}
+	policy := retryPolicyFromDecoupleQueue(dq)
 
-	if topic, ok := m.topics[*broker]; ok {
-		if topic.ID() == topicID {
-			// Topic already updated.
-			return topic, nil
+	topic, ok := m.topics[*broker]
+	if !ok || topic.ID() != dq.Topic {
+		if ok {
+			// Stop old topic.
+			topic.Stop()
 		}
-		// Stop old topic.
-		m.topics[*broker].Stop()
+		topic = m.pubsub.Topic(dq.Topic)
+		// PublishSettings is only safe to set once, here, before the topic's bundler has started
+		// and before any concurrent Send can observe it: the client snapshots it on first
+		// Publish, so writing it again later wouldn't take effect, and doing so while another
+		// Send is publishing through the same topic would race. A per-broker Timeout lives on
+		// retryPolicy instead and is applied per-attempt in publishOnce.
+		topic.PublishSettings = m.publishSettings
+		m.topics[*broker] = topic
 	}
-	topic := m.pubsub.Topic(topicID)
-	m.topics[*broker] = topic
-	return topic, nil
+	// Refresh the cached retry/backoff/timeout policy on every call, not just when the topic ID
+	// changes, so a Retry/BackoffDelay/Timeout edit takes effect on the next Send.
+	m.retries[*broker] = policy
+	return topic, policy, nil
 }
 
-func (m *multiTopicDecoupleSink) getTopicIDForBroker(ctx context.Context, broker *config.BrokerKey) (string, error) {
+// getDecoupleQueueForBroker returns the decouple queue config for the given broker, validating
+// that it is complete and ready to accept publishes.
+func (m *multiTopicDecoupleSink) getDecoupleQueueForBroker(ctx context.Context, broker *config.BrokerKey) (*config.DecoupleQueue, error) {
 	brokerConfig, ok := m.brokerConfig.GetBrokerByKey(broker)
 	if !ok {
 		// There is an propagation delay between the controller reconciles the broker config and
 		// the config being pushed to the configmap volume in the ingress pod. So sometimes we return
 		// an error even if the request is valid.
 		logging.FromContext(ctx).Warn("config is not found for")
-		return "", fmt.Errorf("%q: %w", broker, ErrNotFound)
+		return nil, fmt.Errorf("%q: %w", broker, ErrNotFound)
 	}
 	if brokerConfig.DecoupleQueue == nil || brokerConfig.DecoupleQueue.Topic == "" {
 		logging.FromContext(ctx).Error("DecoupleQueue or topic missing for broker, this should NOT happen.", zap.Any("brokerConfig", brokerConfig))
-		return "", fmt.Errorf("decouple queue of %q: %w", broker, ErrIncomplete)
+		return nil, fmt.Errorf("decouple queue of %q: %w", broker, ErrIncomplete)
 	}
 	if brokerConfig.DecoupleQueue.State != config.State_READY {
 		logging.FromContext(ctx).Debug("decouple queue is not ready")
-		return "", fmt.Errorf("%q: %w", broker, ErrNotReady)
+		return nil, fmt.Errorf("%q: %w", broker, ErrNotReady)
 	}
-	return brokerConfig.DecoupleQueue.Topic, nil
+	return brokerConfig.DecoupleQueue, nil
 }
 
-func (m *multiTopicDecoupleSink) getExistingTopic(broker *config.BrokerKey) (*pubsub.Topic, bool) {
+func (m *multiTopicDecoupleSink) getExistingTopic(broker *config.BrokerKey) (*pubsub.Topic, retryPolicy, bool) {
 	m.topicsMut.RLock()
 	defer m.topicsMut.RUnlock()
 	topic, ok := m.topics[*broker]
-	return topic, ok
+	if !ok {
+		return nil, retryPolicy{}, false
+	}
+	return topic, m.retries[*broker], true
 }
@@ -0,0 +1,138 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// newTestTopic starts an in-memory pstest fake Pub/Sub server and returns a topic backed by it,
+// along with the server. Both are shut down via t.Cleanup.
+func newTestTopic(t *testing.T) (*pubsub.Topic, *pstest.Server) {
+	t.Helper()
+	srv := pstest.NewServer()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dialing pstest server: %v", err)
+	}
+	client, err := pubsub.NewClient(context.Background(), "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("creating pubsub client: %v", err)
+	}
+	topic, err := client.CreateTopic(context.Background(), "test-topic")
+	if err != nil {
+		t.Fatalf("creating topic: %v", err)
+	}
+	t.Cleanup(func() {
+		topic.Stop()
+		conn.Close()
+		srv.Close()
+	})
+	return topic, srv
+}
+
+// failingPublishReactor is a pstest.Reactor that fails the first n Publish calls with err, then
+// lets the fake server handle subsequent calls normally.
+type failingPublishReactor struct {
+	n    int32
+	err  error
+	done int32
+}
+
+func failNTimes(n int32, err error) *failingPublishReactor {
+	return &failingPublishReactor{n: n, err: err}
+}
+
+func (r *failingPublishReactor) React(req interface{}) (handled bool, ret interface{}, err error) {
+	if atomic.AddInt32(&r.done, 1) <= r.n {
+		return true, nil, r.err
+	}
+	return false, nil, nil
+}
+
+func TestPublishWithRetry_SucceedsAfterRetryableFailures(t *testing.T) {
+	topic, srv := newTestTopic(t)
+	srv.SetReactor("Publish", failNTimes(2, status.Error(codes.Unavailable, "try again")))
+
+	policy := retryPolicy{maxRetries: 3, backoffPolicy: config.BackoffPolicy_BACKOFF_POLICY_LINEAR, backoffDelay: time.Millisecond}
+	err := publishWithRetry(context.Background(), topic, &pubsub.Message{Data: []byte("hello")}, policy)
+	if err != nil {
+		t.Errorf("publishWithRetry() = %v, want nil", err)
+	}
+}
+
+func TestPublishWithRetry_GivesUpAfterMaxRetries(t *testing.T) {
+	topic, srv := newTestTopic(t)
+	retryableErr := status.Error(codes.Unavailable, "always fails")
+	srv.SetReactor("Publish", failNTimes(100, retryableErr))
+
+	policy := retryPolicy{maxRetries: 2, backoffPolicy: config.BackoffPolicy_BACKOFF_POLICY_LINEAR, backoffDelay: time.Millisecond}
+	err := publishWithRetry(context.Background(), topic, &pubsub.Message{Data: []byte("hello")}, policy)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("publishWithRetry() = %v, want a codes.Unavailable error", err)
+	}
+}
+
+func TestPublishWithRetry_NonRetryableErrorFailsImmediately(t *testing.T) {
+	topic, srv := newTestTopic(t)
+	nonRetryableErr := status.Error(codes.NotFound, "topic gone")
+	srv.SetReactor("Publish", failNTimes(100, nonRetryableErr))
+
+	policy := retryPolicy{maxRetries: 5, backoffPolicy: config.BackoffPolicy_BACKOFF_POLICY_LINEAR, backoffDelay: time.Second}
+	start := time.Now()
+	err := publishWithRetry(context.Background(), topic, &pubsub.Message{Data: []byte("hello")}, policy)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("publishWithRetry() = %v, want a codes.NotFound error", err)
+	}
+	if elapsed := time.Since(start); elapsed >= policy.backoffDelay {
+		t.Errorf("publishWithRetry() took %v, want it to return before ever sleeping for the retry backoff", elapsed)
+	}
+}
+
+func TestIsRetryablePublishErr(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil error", err: nil, want: true},
+		{name: "not found", err: status.Error(codes.NotFound, "x"), want: false},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "x"), want: false},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "x"), want: true},
+		{name: "plain error", err: errors.New("boom"), want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRetryablePublishErr(test.err); got != test.want {
+				t.Errorf("isRetryablePublishErr(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// probePublishPermission is the IAM permission probeTopic checks for, rather than publishing a
+// synthetic event into the broker's live decouple topic. Nothing in this tree owns the fanout
+// path that would need to recognize and drop a synthetic probe event, so publishing one would
+// deliver a bogus event to every trigger on the broker; a permission check verifies the same
+// "is this topic publishable" property without that side effect.
+const probePublishPermission = "pubsub.topics.publish"
+
+// defaultProbePeriod is how often each cached decouple topic is probed.
+const defaultProbePeriod = 30 * time.Second
+
+// ProbeStatus is the outcome of the most recent health probe of a broker's decouple topic.
+type ProbeStatus struct {
+	Ready         bool
+	LastProbeTime time.Time
+	LastError     error
+}
+
+// prober periodically verifies that every topic cached by a multiTopicDecoupleSink is reachable
+// and publishable, so Send can fail fast instead of blocking on a dead topic.
+type prober struct {
+	sink   *multiTopicDecoupleSink
+	period time.Duration
+
+	mu       sync.RWMutex
+	statuses map[config.BrokerKey]ProbeStatus
+	// cycles counts completed probeAll passes. Ready refuses to report healthy until at least one
+	// has finished, so readiness can't be satisfied vacuously by an empty statuses map before the
+	// first probe has actually run.
+	cycles int
+}
+
+// StartProber launches a background prober for m's cached decouple topics at the given period,
+// storing it on m so that Send and m.ReadyzHandler consult it. The ingress's main binary MUST
+// call StartProber during startup and register m.ReadyzHandler on its /readyz route; neither
+// happens automatically, and until both are wired, Send's ErrNotReady short-circuit stays dead
+// and /readyz is never served. No cmd/ main package exists in this checkout to perform that
+// wiring; these are the integration points it must call.
+func (m *multiTopicDecoupleSink) StartProber(ctx context.Context, period time.Duration) *prober {
+	if period <= 0 {
+		period = defaultProbePeriod
+	}
+	p := &prober{
+		sink:     m,
+		period:   period,
+		statuses: make(map[config.BrokerKey]ProbeStatus),
+	}
+	m.prober = p
+	go p.run(ctx)
+	return p
+}
+
+func (p *prober) run(ctx context.Context) {
+	// Probe once immediately on startup so Ready has a chance to turn healthy well before the
+	// first tick, instead of always waiting a full period.
+	p.probeAll(ctx)
+
+	ticker := time.NewTicker(p.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.probeAll(ctx)
+		}
+	}
+}
+
+func (p *prober) probeAll(ctx context.Context) {
+	p.sink.topicsMut.RLock()
+	brokers := make([]config.BrokerKey, 0, len(p.sink.topics))
+	for b := range p.sink.topics {
+		brokers = append(brokers, b)
+	}
+	p.sink.topicsMut.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, broker := range brokers {
+		broker := broker
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.probeOne(ctx, broker)
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	p.cycles++
+	p.mu.Unlock()
+}
+
+func (p *prober) probeOne(ctx context.Context, broker config.BrokerKey) {
+	topic, _, ok := p.sink.getExistingTopic(&broker)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	err := probeTopic(ctx, topic)
+	latency := time.Since(start)
+
+	status := ProbeStatus{
+		Ready:         err == nil,
+		LastProbeTime: start,
+		LastError:     err,
+	}
+	p.mu.Lock()
+	p.statuses[broker] = status
+	p.mu.Unlock()
+
+	if err != nil {
+		logging.FromContext(ctx).Warn("decouple topic probe failed", zap.String("broker", broker.PersistenceString()), zap.Error(err))
+	}
+	recordProbeMetrics(ctx, broker, latency, status.Ready)
+}
+
+// probeTopic checks that topic exists and is currently publishable, without writing anything to
+// it: a missing topic fails Exists, and a topic the ingress's service account can no longer
+// publish to (e.g. an IAM binding was revoked) fails the TestPermissions check.
+func probeTopic(ctx context.Context, topic *pubsub.Topic) error {
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("checking topic %q exists: %w", topic.ID(), err)
+	}
+	if !exists {
+		return fmt.Errorf("topic %q does not exist", topic.ID())
+	}
+
+	granted, err := topic.IAM().TestPermissions(ctx, []string{probePublishPermission})
+	if err != nil {
+		return fmt.Errorf("checking publish permission for topic %q: %w", topic.ID(), err)
+	}
+	if len(granted) == 0 {
+		return fmt.Errorf("missing %q permission for topic %q", probePublishPermission, topic.ID())
+	}
+	return nil
+}
+
+// Status returns the latest probe status for broker, and whether one has been recorded yet.
+func (p *prober) Status(broker config.BrokerKey) (ProbeStatus, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	status, ok := p.statuses[broker]
+	return status, ok
+}
+
+// Ready reports whether every probed broker is currently healthy. It's false until at least one
+// full probe cycle has completed, so a broker that hasn't been probed yet can't be reported ready
+// by default; once a cycle has run, brokers with no topic yet (and so nothing to probe) don't
+// count against readiness.
+func (p *prober) Ready() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.cycles == 0 {
+		return false
+	}
+	for _, status := range p.statuses {
+		if !status.Ready {
+			return false
+		}
+	}
+	return true
+}
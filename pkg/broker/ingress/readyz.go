@@ -0,0 +1,31 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import "net/http"
+
+// ReadyzHandler serves the ingress's /readyz endpoint. It reports 200 only once StartProber has
+// been called and every probed broker's decouple topic is currently healthy, so that a
+// Kubernetes readiness probe reflects whether the ingress can actually deliver to its brokers,
+// not just whether the process is up.
+func (m *multiTopicDecoupleSink) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if m.prober == nil || !m.prober.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}